@@ -4,13 +4,21 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/gomods/athens/pkg/download/mode"
 	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/index/nop"
 	"github.com/gomods/athens/pkg/log"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/stash"
 	"github.com/gomods/athens/pkg/storage"
+	"github.com/gomods/athens/pkg/storage/mem"
 	"github.com/gorilla/mux"
+	"github.com/spf13/afero"
 )
 
 func TestRedirect(t *testing.T) {
@@ -44,6 +52,55 @@ func TestRedirect(t *testing.T) {
 	}
 }
 
+// TestUpstreamAuthFailure checks that an upstream refusing our
+// credentials is reported as a bad gateway rather than a 401, which
+// would ask the go client to authenticate to Athens.
+func TestUpstreamAuthFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake go binary is a shell script")
+	}
+	goBin := filepath.Join(t.TempDir(), "go")
+	script := `#!/bin/sh
+echo '{"Path":"git.example/a/b","Version":"v1.0.0","Error":"fatal: Authentication failed for https://git.example/a/b.git/"}'
+exit 1
+`
+	if err := os.WriteFile(goBin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	fs := afero.NewOsFs()
+	mf, err := module.NewGoGetFetcher(goBin, t.TempDir(), nil, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := mem.NewStorage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := mux.NewRouter()
+	RegisterHandlers(r, &HandlerOpts{
+		Protocol: New(&Opts{
+			Storage:     s,
+			Stasher:     stash.New(mf, s, nop.New()),
+			Lister:      module.NewVCSLister(goBin, nil, fs),
+			NetworkMode: Strict,
+		}),
+		Logger:       log.NoOpLogger(),
+		DownloadFile: &mode.DownloadFile{Mode: mode.Sync},
+	})
+	for _, path := range [...]string{
+		"/git.example/a/b/@v/v1.0.0.info",
+		"/git.example/a/b/@v/v1.0.0.mod",
+		"/git.example/a/b/@v/v1.0.0.zip",
+	} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadGateway {
+			t.Fatalf("%s: expected a bad gateway status (502) but got %v", path, w.Code)
+		}
+	}
+}
+
 type mockProtocol struct {
 	Protocol
 }
@@ -18,6 +18,17 @@ const (
 	KindRateLimit      = http.StatusTooManyRequests
 	KindNotImplemented = http.StatusNotImplemented
 	KindRedirect       = http.StatusMovedPermanently
+	// KindServiceUnavailable marks transient failures that are
+	// expected to succeed if the operation is retried.
+	KindServiceUnavailable = http.StatusServiceUnavailable
+	// KindTooLarge marks content that exceeds a configured size limit.
+	KindTooLarge = http.StatusRequestEntityTooLarge
+	// KindTimeout marks operations that did not finish in their allotted time.
+	KindTimeout = http.StatusGatewayTimeout
+	// KindBadGateway marks upstreams we could reach but not trust or
+	// talk to, e.g. because of TLS certificate errors or because they
+	// refused our credentials.
+	KindBadGateway = http.StatusBadGateway
 )
 
 // Error is an Athens system error.
@@ -0,0 +1,19 @@
+package module
+
+import (
+	"context"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
+	"go.opencensus.io/trace"
+)
+
+// sanitizeError logs err and records it on the active span, then returns
+// an error of the same kind that only carries msg, so that the details of
+// err are not passed on to clients.
+func sanitizeError(ctx context.Context, err error, mod, ver, msg string) error {
+	const op errors.Op = "module.sanitizeError"
+	log.EntryFromContext(ctx).SystemErr(err)
+	trace.FromContext(ctx).AddAttributes(trace.StringAttribute("fetch.error", err.Error()))
+	return errors.E(op, errors.M(mod), errors.V(ver), msg, errors.Kind(err))
+}
@@ -0,0 +1,71 @@
+package compliance
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/mod/modfile"
+)
+
+// RunTests takes a Fetcher implementation and runs compliance tests
+// against the interface. f must be able to fetch mod@ver, a module with
+// a go.mod file, and must not know of its zero pseudo-version.
+func RunTests(t *testing.T, f module.Fetcher, mod, ver string) {
+	t.Helper()
+	testFetch(t, f, mod, ver)
+	testNotFound(t, f, mod)
+	testBadRequest(t, f, ver)
+}
+
+// testFetch ensures that a Fetcher returns the artifacts of the
+// version it was asked for, with a zip that the caller can read and close.
+func testFetch(t *testing.T, f module.Fetcher, mod, ver string) {
+	t.Helper()
+	ctx := context.Background()
+	v, err := f.Fetch(ctx, mod, ver)
+	require.NoError(t, err)
+	require.Equal(t, ver, v.Semver)
+
+	var info struct{ Version string }
+	require.NoError(t, json.Unmarshal(v.Info, &info), "the .info file is not valid JSON")
+	require.Equal(t, ver, info.Version)
+	require.Equal(t, mod, modfile.ModulePath(v.Mod))
+
+	require.NotNil(t, v.Zip)
+	zipBytes, err := io.ReadAll(v.Zip)
+	require.NoError(t, err)
+	require.NoError(t, v.Zip.Close())
+	zr, err := zip.NewReader(bytes.NewReader(zipBytes), int64(len(zipBytes)))
+	require.NoError(t, err, "the zip is not a valid zip file")
+	require.NotEmpty(t, zr.File)
+	for _, zf := range zr.File {
+		require.True(t, strings.HasPrefix(zf.Name, mod+"@"+ver+"/"), "zip entry %s is outside of %s@%s/", zf.Name, mod, ver)
+	}
+}
+
+// testNotFound ensures that a Fetcher returns a KindNotFound
+// error when asked for a version that does not exist.
+func testNotFound(t *testing.T, f module.Fetcher, mod string) {
+	t.Helper()
+	_, err := f.Fetch(context.Background(), mod, "v0.0.0-00010101000000-000000000000")
+	require.Error(t, err)
+	require.Equal(t, errors.KindNotFound, errors.Kind(err))
+}
+
+// testBadRequest ensures that a Fetcher returns a KindBadRequest
+// error when asked for a malformed module path or version.
+func testBadRequest(t *testing.T, f module.Fetcher, ver string) {
+	t.Helper()
+	ctx := context.Background()
+	_, err := f.Fetch(ctx, "../../etc/passwd", ver)
+	require.Error(t, err)
+	require.Equal(t, errors.KindBadRequest, errors.Kind(err))
+}
@@ -0,0 +1,73 @@
+package module_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gobuffalo/envy"
+	"github.com/gomods/athens/pkg/module"
+	"github.com/gomods/athens/pkg/module/compliance"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	mockPath  = "mockmod.xyz"
+	mockVer   = "v1.2.3"
+	mockInfo  = `{"Version":"v1.2.3"}`
+	mockGoMod = "module mockmod.xyz\n"
+)
+
+func mockZip(t *testing.T) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"go.mod": mockGoMod,
+		"mod.go": "package mod\n",
+	} {
+		w, err := zw.Create(mockPath + "@" + mockVer + "/" + name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestFileFetcherCompliance(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	base := filepath.Join("/seed", mockPath, "@v", mockVer)
+	require.NoError(t, afero.WriteFile(fs, base+".info", []byte(mockInfo), 0o644))
+	require.NoError(t, afero.WriteFile(fs, base+".mod", []byte(mockGoMod), 0o644))
+	require.NoError(t, afero.WriteFile(fs, base+".zip", mockZip(t), 0o644))
+
+	compliance.RunTests(t, module.NewFileFetcher(fs, "/seed"), mockPath, mockVer)
+}
+
+func TestGoGetFetcherCompliance(t *testing.T) {
+	files := map[string][]byte{
+		"/mockmod.xyz/@v/v1.2.3.info": []byte(mockInfo),
+		"/mockmod.xyz/@v/v1.2.3.mod":  []byte(mockGoMod),
+		"/mockmod.xyz/@v/v1.2.3.zip":  mockZip(t),
+	}
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(content)
+	}))
+	defer proxy.Close()
+
+	goBin := envy.Get("GO_BINARY_PATH", "go")
+	env := []string{"GONOSUMDB=" + mockPath, "GOPROXY=" + proxy.URL}
+	fetcher, err := module.NewGoGetFetcher(goBin, "", env, afero.NewOsFs())
+	require.NoError(t, err)
+
+	compliance.RunTests(t, fetcher, mockPath, mockVer)
+}
@@ -0,0 +1,16 @@
+package module
+
+import "time"
+
+// FetchObserver is notified of the outcome of every fetch made by the
+// go get fetcher, e.g. to export the count and latency of fetches per
+// outcome as metrics. kind is the errors.Kind of the error the fetch
+// failed with, or 0 if it succeeded. dur covers the whole fetch,
+// including retries.
+type FetchObserver interface {
+	ObserveFetch(mod, ver string, dur time.Duration, kind int)
+}
+
+type noopFetchObserver struct{}
+
+func (noopFetchObserver) ObserveFetch(string, string, time.Duration, int) {}
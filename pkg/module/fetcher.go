@@ -7,8 +7,26 @@ import (
 )
 
 // Fetcher fetches module from an upstream source.
+// The compliance package holds tests that every implementation should pass.
 type Fetcher interface {
 	// Fetch downloads the sources from an upstream and returns the corresponding
 	// .info, .mod, and .zip files.
+	//
+	// The returned Version's Semver is the version that was fetched, and its Zip
+	// is owned by the caller, who must close it to free the resources behind it.
+	// Fetch fails with a KindNotFound error if mod@ver does not exist upstream,
+	// and with a KindBadRequest error if mod or ver are malformed.
 	Fetch(ctx context.Context, mod, ver string) (*storage.Version, error)
 }
+
+// DebugFetcher is implemented by fetchers that can expose the raw
+// result of downloading a module, for troubleshooting purposes.
+type DebugFetcher interface {
+	FetchDebug(ctx context.Context, mod, ver string) (*GoModule, error)
+}
+
+// InfoFetcher is implemented by fetchers that can resolve a module
+// version and return its metadata without handing out its zip.
+type InfoFetcher interface {
+	FetchInfo(ctx context.Context, mod, ver string) (*storage.Version, error)
+}
@@ -0,0 +1,73 @@
+package module
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/observ"
+	"github.com/gomods/athens/pkg/storage"
+	"github.com/spf13/afero"
+	"golang.org/x/mod/module"
+)
+
+type fileFetcher struct {
+	fs   afero.Fs
+	root string
+}
+
+// NewFileFetcher creates a fetcher which reads modules from a directory
+// instead of the network, for seeding air-gapped deployments. The directory
+// must have the layout of a GOPROXY or of a module download cache
+// ($GOPATH/pkg/mod/cache/download): every version is stored as
+// <root>/<escaped module>/@v/<escaped version>.{info,mod,zip}.
+func NewFileFetcher(fs afero.Fs, root string) Fetcher {
+	return &fileFetcher{fs: fs, root: root}
+}
+
+// Fetch reads the .info, .mod, and .zip files of mod@ver from disk.
+// The returned zip is read straight from disk and must be closed by the caller.
+func (f *fileFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "fileFetcher.Fetch"
+	_, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+
+	escMod, err := module.EscapePath(mod)
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), err, errors.KindBadRequest)
+	}
+	escVer, err := module.EscapeVersion(ver)
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), err, errors.KindBadRequest)
+	}
+	base := filepath.Join(f.root, filepath.FromSlash(escMod), "@v", escVer)
+
+	info, err := afero.ReadFile(f.fs, base+".info")
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), err, readErrKind(err))
+	}
+	gomod, err := afero.ReadFile(f.fs, base+".mod")
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), err, readErrKind(err))
+	}
+	zip, err := f.fs.Open(base + ".zip")
+	if err != nil {
+		return nil, errors.E(op, errors.M(mod), errors.V(ver), err, readErrKind(err))
+	}
+	return &storage.Version{
+		Semver: ver,
+		Info:   info,
+		Mod:    gomod,
+		Zip:    zip,
+	}, nil
+}
+
+// readErrKind reports a missing file as KindNotFound. Any other
+// error, such as a permission problem, is unexpected.
+func readErrKind(err error) int {
+	if os.IsNotExist(err) {
+		return errors.KindNotFound
+	}
+	return errors.KindUnexpected
+}
@@ -0,0 +1,46 @@
+package module
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+func (s *ModuleSuite) TestFileFetcherFetch() {
+	r := s.Require()
+	fs := afero.NewMemMapFs()
+	// upper case letters are escaped with a '!' on disk
+	base := filepath.Join("/seed", "github.com", "!n!y!times", "gizmo", "@v", "v0.1.4")
+	r.NoError(createAndWriteFile(fs, base+".info", `{"Version":"v0.1.4"}`))
+	r.NoError(createAndWriteFile(fs, base+".mod", "module github.com/NYTimes/gizmo"))
+	r.NoError(createAndWriteFile(fs, base+".zip", "zip"))
+
+	fetcher := NewFileFetcher(fs, "/seed")
+	ver, err := fetcher.Fetch(ctx, "github.com/NYTimes/gizmo", "v0.1.4")
+	r.NoError(err)
+	defer ver.Zip.Close()
+
+	r.Equal("v0.1.4", ver.Semver)
+	r.Equal(`{"Version":"v0.1.4"}`, string(ver.Info))
+	r.Equal("module github.com/NYTimes/gizmo", string(ver.Mod))
+	zip, err := io.ReadAll(ver.Zip)
+	r.NoError(err)
+	r.Equal("zip", string(zip))
+
+	_, err = fetcher.Fetch(ctx, "github.com/NYTimes/gizmo", "v0.1.5")
+	r.Equal(errors.KindNotFound, errors.Kind(err))
+}
+
+func (s *ModuleSuite) TestFileFetcherReadError() {
+	r := s.Require()
+	root := s.T().TempDir()
+	// a directory in place of the .info file cannot be read
+	r.NoError(os.MkdirAll(filepath.Join(root, "mockmod.xyz", "@v", "v1.2.3.info"), 0o755))
+
+	_, err := NewFileFetcher(afero.NewOsFs(), root).Fetch(ctx, "mockmod.xyz", "v1.2.3")
+	r.Error(err)
+	r.Equal(errors.KindUnexpected, errors.Kind(err))
+}
@@ -5,96 +5,264 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
 	"github.com/gomods/athens/pkg/observ"
 	"github.com/gomods/athens/pkg/storage"
 	"github.com/spf13/afero"
+	"go.opencensus.io/trace"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb/dirhash"
 )
 
 type goGetFetcher struct {
-	fs           afero.Fs
-	goBinaryName string
-	envVars      []string
-	gogetDir     string
+	fs              afero.Fs
+	goBinaryName    string
+	envVars         []string
+	gogetDir        string
+	maxZipSize      int64
+	recorder        CommandRecorder
+	redactKeys      []string
+	staleAge        time.Duration
+	staleLog        log.Entry
+	traceErrors     bool
+	transform       SourceTransform
+	retries         int
+	retryDelay      time.Duration
+	checkModPath    bool
+	downloadTimeout time.Duration
+	repoDirName     RepoDirNamer
+	treeHash        bool
+	checkVersions   bool
+	memZipMax       int64
+	clientMessages  map[int]string
+	observer        FetchObserver
 }
 
-type goModule struct {
-	Path     string `json:"path"`     // module path
-	Version  string `json:"version"`  // module version
-	Error    string `json:"error"`    // error loading module
-	Info     string `json:"info"`     // absolute path to cached .info file
-	GoMod    string `json:"goMod"`    // absolute path to cached .mod file
-	Zip      string `json:"zip"`      // absolute path to cached .zip file
-	Dir      string `json:"dir"`      // absolute path to cached source root directory
-	Sum      string `json:"sum"`      // checksum for path, version (as in go.sum)
-	GoModSum string `json:"goModSum"` // checksum for go.mod (as in go.sum)
+// GoModule is the output of 'go mod download -json' for a single module.
+type GoModule struct {
+	Path     string          `json:"path"`     // module path
+	Version  string          `json:"version"`  // module version
+	Error    string          `json:"error"`    // error loading module
+	Info     string          `json:"info"`     // absolute path to cached .info file
+	GoMod    string          `json:"goMod"`    // absolute path to cached .mod file
+	Zip      string          `json:"zip"`      // absolute path to cached .zip file
+	Dir      string          `json:"dir"`      // absolute path to cached source root directory
+	Sum      string          `json:"sum"`      // checksum for path, version (as in go.sum)
+	GoModSum string          `json:"goModSum"` // checksum for go.mod (as in go.sum)
+	Origin   *storage.Origin `json:"origin"`   // provenance of module, if known
 }
 
 // NewGoGetFetcher creates fetcher which uses go get tool to fetch modules.
-func NewGoGetFetcher(goBinaryName, gogetDir string, envVars []string, fs afero.Fs) (Fetcher, error) {
+// Optional behavior can be turned on by passing FetcherOptions.
+func NewGoGetFetcher(goBinaryName, gogetDir string, envVars []string, fs afero.Fs, opts ...FetcherOption) (Fetcher, error) {
 	const op errors.Op = "module.NewGoGetFetcher"
 	if err := validGoBinary(goBinaryName); err != nil {
 		return nil, errors.E(op, err)
 	}
-	return &goGetFetcher{
+	g := &goGetFetcher{
 		fs:           fs,
 		goBinaryName: goBinaryName,
 		envVars:      envVars,
 		gogetDir:     gogetDir,
-	}, nil
+		redactKeys:   defaultRedactKeys,
+		repoDirName:  getRepoDirName,
+		observer:     noopFetchObserver{},
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if g.staleAge > 0 {
+		if err := clearStaleGoPaths(g.fs, g.gogetDir, g.staleAge, g.staleLog); err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
+	return g, nil
 }
 
+// latestQuery is the version query that resolves to the latest
+// version of a module.
+const latestQuery = "latest"
+
 // Fetch downloads the sources from the go binary and returns the corresponding
-// .info, .mod, and .zip files.
+// .info, .mod, and .zip files. If ver is empty or "latest", the latest version
+// of mod is fetched and the version it resolved to is returned as Semver.
 func (g *goGetFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Version, error) {
 	const op errors.Op = "goGetFetcher.Fetch"
 	ctx, span := observ.StartSpan(ctx, op.String())
 	defer span.End()
 
+	v, err := g.runFetch(ctx, mod, ver, func(ctx context.Context, mod, ver string) (*storage.Version, error) {
+		return g.fetch(ctx, mod, ver, true)
+	})
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return v, nil
+}
+
+// FetchInfo is like Fetch, but only returns the .info and .mod files of
+// mod@ver and the checksum of the latter. It resolves them with
+// 'go list -m -json', so the zip is never downloaded: the returned Zip is
+// nil, and Sum and TreeHash are left empty. The temporary GOPATH is
+// removed before returning.
+func (g *goGetFetcher) FetchInfo(ctx context.Context, mod, ver string) (*storage.Version, error) {
+	const op errors.Op = "goGetFetcher.FetchInfo"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+
+	v, err := g.runFetch(ctx, mod, ver, func(ctx context.Context, mod, ver string) (*storage.Version, error) {
+		return g.fetch(ctx, mod, ver, false)
+	})
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return v, nil
+}
+
+// runFetch runs fetch for mod@ver, reports its outcome to the fetcher's
+// observer and prepares the error it fails with, if any, for clients.
+func (g *goGetFetcher) runFetch(ctx context.Context, mod, ver string, fetch func(ctx context.Context, mod, ver string) (*storage.Version, error)) (*storage.Version, error) {
+	if ver == "" {
+		ver = latestQuery
+	}
+
+	start := time.Now()
+	v, err := fetch(ctx, mod, ver)
+	if err != nil {
+		g.observer.ObserveFetch(mod, ver, time.Since(start), errors.Kind(err))
+		if msg, ok := g.clientMessages[errors.Kind(err)]; ok {
+			err = sanitizeError(ctx, err, mod, ver, msg)
+		}
+		if g.traceErrors {
+			err = withTraceID(ctx, err)
+		}
+		return nil, err
+	}
+	g.observer.ObserveFetch(mod, ver, time.Since(start), 0)
+	return v, nil
+}
+
+// fetch downloads mod@ver and reads its artifacts. Unless withZip is set, only
+// the .info and .mod files are downloaded, see FetchInfo.
+func (g *goGetFetcher) fetch(ctx context.Context, mod, ver string, withZip bool) (*storage.Version, error) {
+	const op errors.Op = "goGetFetcher.fetch"
+	if err := checkModVer(mod, ver); err != nil {
+		return nil, errors.E(op, err)
+	}
+
 	// setup the GOPATH
-	goPathRoot, err := afero.TempDir(g.fs, g.gogetDir, "athens")
+	goPathRoot, err := afero.TempDir(g.fs, g.gogetDir, goPathPrefix)
 	if err != nil {
 		return nil, errors.E(op, err)
 	}
+	// remove the GOPATH on every return, unless it is handed over to the zip
+	keepGoPath := false
+	defer func() {
+		if !keepGoPath {
+			_ = clearFiles(g.fs, goPathRoot)
+		}
+	}()
 	sourcePath := filepath.Join(goPathRoot, "src")
-	modPath := filepath.Join(sourcePath, getRepoDirName(mod, ver))
+	modPath := filepath.Join(sourcePath, g.repoDirName(mod, ver))
 	if err := g.fs.MkdirAll(modPath, os.ModeDir|os.ModePerm); err != nil {
-		_ = clearFiles(g.fs, goPathRoot)
 		return nil, errors.E(op, err)
 	}
 
-	m, err := downloadModule(
-		ctx,
-		g.goBinaryName,
-		g.envVars,
-		goPathRoot,
-		modPath,
-		mod,
-		ver,
-	)
+	m, err := g.downloadModule(ctx, goPathRoot, modPath, mod, ver, !withZip)
 	if err != nil {
-		_ = clearFiles(g.fs, goPathRoot)
 		return nil, errors.E(op, err)
 	}
+	if err := m.checkArtifacts(withZip); err != nil {
+		return nil, errors.E(op, err)
+	}
+	var treeHash string
+	if withZip {
+		if g.treeHash {
+			// hash the tree before a transform gets to work on its copy of it
+			if treeHash, err = hashTree(g.fs, m); err != nil {
+				return nil, errors.E(op, err)
+			}
+		}
+		if g.transform != nil {
+			if m.Zip, err = g.transformSource(ctx, m, goPathRoot); err != nil {
+				return nil, errors.E(op, err)
+			}
+			// the checksum reported by the go command is the one of the original zip
+			if m.Sum, err = hashZip(g.fs, m.Zip); err != nil {
+				return nil, errors.E(op, err)
+			}
+		}
+		if err := g.checkZipSize(m); err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
 
 	var storageVer storage.Version
 	storageVer.Semver = m.Version
+	storageVer.Sum = m.Sum
+	storageVer.GoModSum = m.GoModSum
+	storageVer.TreeHash = treeHash
+	storageVer.Origin = m.Origin
 	info, err := afero.ReadFile(g.fs, m.Info)
 	if err != nil {
 		return nil, errors.E(op, err)
 	}
+	if g.checkVersions && withZip {
+		if err := checkVersionConsistency(g.fs, m, info); err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
 	storageVer.Info = info
 
 	gomod, err := afero.ReadFile(g.fs, m.GoMod)
 	if err != nil {
 		return nil, errors.E(op, err)
 	}
+	if g.checkModPath {
+		if err := checkModulePath(m.Path, gomod); err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
 	storageVer.Mod = gomod
+	if storageVer.GoModSum == "" {
+		// go list does not report it
+		if storageVer.GoModSum, err = goModSum(gomod); err != nil {
+			return nil, errors.E(op, err)
+		}
+	}
+
+	// the caller may have given up while we were busy
+	if err := ctx.Err(); err != nil {
+		return nil, errors.E(op, err)
+	}
+	if !withZip {
+		return &storageVer, nil
+	}
+
+	if g.memZipMax > 0 {
+		fi, err := g.fs.Stat(m.Zip)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+		if fi.Size() <= g.memZipMax {
+			zip, err := afero.ReadFile(g.fs, m.Zip)
+			if err != nil {
+				return nil, errors.E(op, err)
+			}
+			storageVer.Zip = memZip{bytes.NewReader(zip)}
+			return &storageVer, nil
+		}
+	}
 
 	zip, err := g.fs.Open(m.Zip)
 	if err != nil {
@@ -103,70 +271,405 @@ func (g *goGetFetcher) Fetch(ctx context.Context, mod, ver string) (*storage.Ver
 	// note: don't close zip here so that the caller can read directly from disk.
 	//
 	// if we close, then the caller will panic, and the alternative to make this work is
-	// that we read into memory and return an io.ReadCloser that reads out of memory
-	storageVer.Zip = &zipReadCloser{zip, g.fs, goPathRoot}
+	// that we read into memory and return an io.ReadCloser that reads out of memory.
+	// Closing the returned zip removes the whole temporary GOPATH, including the
+	// module sources the go command extracted under it.
+	storageVer.Zip = &zipReadCloser{zip: zip, fs: g.fs, goPath: goPathRoot}
+	keepGoPath = true
 
 	return &storageVer, nil
 }
 
-// given a filesystem, gopath, repository root, module and version, runs 'go mod download -json'
+// FetchDebug runs 'go mod download -json' for mod@ver and returns its decoded
+// output as is, without reading or packaging any of the downloaded files.
+// It is meant for troubleshooting fetches: the temporary GOPATH is removed
+// before returning, so the file paths in the result no longer exist.
+func (g *goGetFetcher) FetchDebug(ctx context.Context, mod, ver string) (*GoModule, error) {
+	const op errors.Op = "goGetFetcher.FetchDebug"
+	ctx, span := observ.StartSpan(ctx, op.String())
+	defer span.End()
+	if ver == "" {
+		ver = latestQuery
+	}
+	if err := checkModVer(mod, ver); err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	goPathRoot, err := afero.TempDir(g.fs, g.gogetDir, goPathPrefix)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	defer func() { _ = clearFiles(g.fs, goPathRoot) }()
+	modPath := filepath.Join(goPathRoot, "src", g.repoDirName(mod, ver))
+	if err := g.fs.MkdirAll(modPath, os.ModeDir|os.ModePerm); err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	m, err := g.downloadModule(ctx, goPathRoot, modPath, mod, ver, false)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+	return &m, nil
+}
+
+// given a gopath, repository root, module and version, runs 'go mod download -json'
 // on module@version from the repoRoot with GOPATH=gopath, and returns a non-nil error if anything went wrong.
-func downloadModule(
-	ctx context.Context,
-	goBinaryName string,
-	envVars []string,
-	gopath,
-	repoRoot,
-	module,
-	version string,
-) (goModule, error) {
-	const op errors.Op = "module.downloadModule"
+// With infoOnly, 'go list -m -json' is run instead, see goModDownload.
+// Transient failures are retried with exponential backoff if the fetcher was configured to do so.
+func (g *goGetFetcher) downloadModule(ctx context.Context, gopath, repoRoot, module, version string, infoOnly bool) (GoModule, error) {
+	const op errors.Op = "goGetFetcher.downloadModule"
+	delay := g.retryDelay
+	for attempt := 0; ; attempt++ {
+		m, err := g.goModDownload(ctx, gopath, repoRoot, module, version, infoOnly)
+		if err == nil {
+			return m, nil
+		}
+		if attempt >= g.retries || !errors.Is(err, errors.KindServiceUnavailable) {
+			return GoModule{}, errors.E(op, err)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return GoModule{}, errors.E(op, err)
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
 
+// goModDownload runs 'go mod download -json' once, see downloadModule.
+// With infoOnly, it runs 'go list -m -json' instead, which resolves the
+// version and only fetches its .info and .mod files. The result then has
+// no Zip, Dir, Sum or GoModSum.
+func (g *goGetFetcher) goModDownload(ctx context.Context, gopath, repoRoot, module, version string, infoOnly bool) (GoModule, error) {
+	const op errors.Op = "goGetFetcher.goModDownload"
 	uri := strings.TrimSuffix(module, "/")
 	fullURI := fmt.Sprintf("%s@%s", uri, version)
+	goCmd, args := "go mod download", []string{"mod", "download", "-json", fullURI}
+	if infoOnly {
+		// -e reports errors in the JSON output, as go mod download does
+		goCmd, args = "go list -m", []string{"list", "-m", "-e", "-json", fullURI}
+	}
 
-	cmd := exec.CommandContext(ctx, goBinaryName, "mod", "download", "-json", fullURI)
-	cmd.Env = prepareEnv(gopath, envVars)
+	cmdCtx := ctx
+	if g.downloadTimeout > 0 {
+		var cancel context.CancelFunc
+		cmdCtx, cancel = context.WithTimeout(ctx, g.downloadTimeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(cmdCtx, g.goBinaryName, args...)
+	cmd.Env = prepareEnv(gopath, g.envVars)
 	cmd.Dir = repoRoot
 	stdout := &bytes.Buffer{}
 	stderr := &bytes.Buffer{}
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
+	g.recordCommand(ctx, cmd)
 
 	err := cmd.Run()
+	if toolchain, ok := toolchainSwitch(stderr.String()); ok {
+		trace.FromContext(ctx).AddAttributes(trace.StringAttribute("go.toolchain", toolchain))
+		log.EntryFromContext(ctx).WithFields(map[string]any{"toolchain": toolchain}).Warnf("go switched to toolchain %s while downloading %s", toolchain, fullURI)
+	}
+	if err != nil && ctx.Err() == nil && errors.IsErr(cmdCtx.Err(), context.DeadlineExceeded) {
+		msg := fmt.Sprintf("%s %s timed out after %v", goCmd, fullURI, g.downloadTimeout)
+		return GoModule{}, errors.E(op, msg, errors.KindTimeout)
+	}
 	if err != nil {
 		err = fmt.Errorf("%w: %s", err, stderr)
-		var m goModule
-		if jsonErr := json.NewDecoder(stdout).Decode(&m); jsonErr != nil {
-			return goModule{}, errors.E(op, err)
-		}
-		// github quota exceeded
-		if isLimitHit(m.Error) {
-			return goModule{}, errors.E(op, m.Error, errors.KindRateLimit)
+		m, jsonErr := decodeGoModule(stdout, infoOnly)
+		if jsonErr != nil {
+			if isTransient(stderr.String()) {
+				return GoModule{}, errors.E(op, err, errors.KindServiceUnavailable)
+			}
+			return GoModule{}, errors.E(op, err)
 		}
-		return goModule{}, errors.E(op, m.Error, errors.KindNotFound)
+		return GoModule{}, errors.E(op, moduleErr(ctx, fullURI, m.Error))
 	}
 
-	var m goModule
-	if err = json.NewDecoder(stdout).Decode(&m); err != nil {
-		return goModule{}, errors.E(op, err)
+	m, err := decodeGoModule(stdout, infoOnly)
+	if err != nil {
+		return GoModule{}, errors.E(op, err)
 	}
 	if m.Error != "" {
-		return goModule{}, errors.E(op, m.Error)
+		if !infoOnly {
+			return GoModule{}, errors.E(op, m.Error)
+		}
+		// go list -e succeeds even if the module could not be loaded
+		return GoModule{}, errors.E(op, moduleErr(ctx, fullURI, m.Error))
 	}
 
 	return m, nil
 }
 
+// moduleErr returns the error the go command reported for fullURI,
+// classified by downloadErrKind.
+func moduleErr(ctx context.Context, fullURI, msg string) error {
+	const op errors.Op = "module.moduleErr"
+	kind := downloadErrKind(msg)
+	if kind == errors.KindRateLimit {
+		host, _ := limitHitHost(msg)
+		log.EntryFromContext(ctx).WithFields(map[string]any{"upstream": host}).Warnf("rate limited by %s while downloading %s", host, fullURI)
+	}
+	return errors.E(op, msg, kind)
+}
+
+// listedModule is the output of 'go list -m -json' for a single module.
+type listedModule struct {
+	Path    string
+	Version string
+	GoMod   string
+	Origin  *storage.Origin
+	Error   *struct{ Err string }
+}
+
+// decodeGoModule decodes the JSON output of 'go mod download', or of
+// 'go list -m' if listed is set. The .info file go list does not report
+// is the one next to the .mod file in the module cache.
+func decodeGoModule(r io.Reader, listed bool) (GoModule, error) {
+	var m GoModule
+	if !listed {
+		err := json.NewDecoder(r).Decode(&m)
+		return m, err
+	}
+	var l listedModule
+	if err := json.NewDecoder(r).Decode(&l); err != nil {
+		return m, err
+	}
+	m = GoModule{Path: l.Path, Version: l.Version, GoMod: l.GoMod, Origin: l.Origin}
+	if l.GoMod != "" {
+		m.Info = strings.TrimSuffix(l.GoMod, ".mod") + ".info"
+	}
+	if l.Error != nil {
+		m.Error = l.Error.Err
+	}
+	return m, nil
+}
+
+// goModSum computes the go.sum checksum of the go.mod file gomod.
+func goModSum(gomod []byte) (string, error) {
+	return dirhash.Hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(gomod)), nil
+	})
+}
+
+// checkArtifacts makes sure the go command reported a path for every
+// artifact we need to serve, so that we never try to read an empty path.
+// The zip is only needed withZip.
+func (m GoModule) checkArtifacts(withZip bool) error {
+	const op errors.Op = "module.checkArtifacts"
+	missing := func(artifact string) error {
+		return errors.E(op, errors.M(m.Path), errors.V(m.Version), fmt.Sprintf("go mod download did not return a %s file for %s@%s", artifact, m.Path, m.Version), errors.KindUnexpected)
+	}
+	switch {
+	case m.Info == "":
+		return missing(".info")
+	case m.GoMod == "":
+		return missing(".mod")
+	case withZip && m.Zip == "":
+		return missing(".zip")
+	}
+	return nil
+}
+
+// downloadErrKind classifies the error message reported by go mod download.
+func downloadErrKind(msg string) int {
+	switch {
+	case isLimitHit(msg):
+		// upstream quota exceeded
+		return errors.KindRateLimit
+	case isTransient(msg):
+		return errors.KindServiceUnavailable
+	case containsAny(msg, authFailureMessages), containsAny(msg, badGatewayMessages):
+		return errors.KindBadGateway
+	}
+	return errors.KindNotFound
+}
+
+// authFailureMessages are fragments of the errors printed when an
+// upstream rejects our credentials. They map to KindBadGateway rather
+// than KindUnauthorized since the kind ends up as the status Athens
+// answers with, and a 401 would ask the go client to authenticate to
+// Athens itself. The "terminal prompts disabled"
+// error is deliberately left out since GitHub asks for credentials
+// for repositories that do not exist too.
+var authFailureMessages = []string{
+	"Authentication failed for",
+	"401 Unauthorized",
+	"returned error: 401",
+	"HTTP Basic: Access denied",
+	"Permission denied (publickey",
+}
+
+// badGatewayMessages are fragments of the errors printed when an
+// upstream can be reached but not talked to. Retrying will not help
+// until someone fixes the upstream or our configuration.
+var badGatewayMessages = []string{
+	"x509: ",
+	"tls: failed to verify certificate",
+	"server certificate verification failed",
+	"SSL certificate problem",
+}
+
+func containsAny(o string, msgs []string) bool {
+	for _, msg := range msgs {
+		if strings.Contains(o, msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTransient reports whether o describes a failure that is
+// likely to go away if go mod download is run again.
+func isTransient(o string) bool {
+	return isCacheLocked(o) || isNetworkFlake(o)
+}
+
+// checkModVer returns a KindBadRequest error unless mod is a valid module
+// path and ver a valid version query, so that neither can be used to escape
+// the temporary GOPATH they are turned into directories of. Besides semantic
+// versions, ver may be any query the go command resolves, such as a branch
+// name or a commit hash.
+func checkModVer(mod, ver string) error {
+	const op errors.Op = "module.checkModVer"
+	if err := module.CheckPath(mod); err != nil {
+		return errors.E(op, errors.M(mod), errors.V(ver), err, errors.KindBadRequest)
+	}
+	if _, err := module.EscapeVersion(ver); err != nil {
+		return errors.E(op, errors.M(mod), errors.V(ver), err, errors.KindBadRequest)
+	}
+	return nil
+}
+
+// checkModulePath returns an error if the module directive
+// of gomod does not declare the module path mod.
+func checkModulePath(mod string, gomod []byte) error {
+	const op errors.Op = "module.checkModulePath"
+	declared := modfile.ModulePath(gomod)
+	if declared != mod {
+		msg := fmt.Sprintf("go.mod declares module path %q but was fetched as %q", declared, mod)
+		return errors.E(op, errors.M(mod), msg, errors.KindUnexpected)
+	}
+	return nil
+}
+
+// checkZipSize returns a KindTooLarge error if the downloaded
+// zip is bigger than the fetcher's configured maximum.
+func (g *goGetFetcher) checkZipSize(m GoModule) error {
+	const op errors.Op = "goGetFetcher.checkZipSize"
+	if g.maxZipSize <= 0 {
+		return nil
+	}
+	fi, err := g.fs.Stat(m.Zip)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	if fi.Size() > g.maxZipSize {
+		msg := fmt.Sprintf("module zip is %d bytes, exceeding the limit of %d bytes", fi.Size(), g.maxZipSize)
+		return errors.E(op, errors.M(m.Path), errors.V(m.Version), msg, errors.KindTooLarge)
+	}
+	return nil
+}
+
 func isLimitHit(o string) bool {
-	return strings.Contains(o, "403 response from api.github.com")
+	_, ok := limitHitHost(o)
+	return ok
+}
+
+// rateLimitMessages are fragments of the errors printed when GitLab
+// or Bitbucket throttle the go command or git.
+var rateLimitMessages = []string{
+	"returned error: 429",
+	"429 Too Many Requests",
+	"Retry later",
+	"Retry-After",
+	"Rate limit for this resource has been exceeded",
+}
+
+var upstreamHost = regexp.MustCompile(`https?://([^/'"\s:]+)`)
+
+// limitHitHost reports whether o indicates that an upstream rate limited
+// us and, if it can tell, which host did.
+func limitHitHost(o string) (string, bool) {
+	if strings.Contains(o, "403 response from api.github.com") {
+		return "api.github.com", true
+	}
+	for _, msg := range rateLimitMessages {
+		if strings.Contains(o, msg) {
+			var host string
+			if m := upstreamHost.FindStringSubmatch(o); m != nil {
+				host = m[1]
+			}
+			return host, true
+		}
+	}
+	return "", false
+}
+
+// networkFlakeMessages are fragments of the errors the go command and
+// the VCS tools it runs print when the network or the upstream server
+// is temporarily failing. Unknown hosts are deliberately left out since
+// they are indistinguishable from modules that do not exist.
+var networkFlakeMessages = []string{
+	"i/o timeout",
+	"TLS handshake timeout",
+	"connection reset by peer",
+	"connection refused",
+	"Connection timed out",
+	"unexpected EOF",
+}
+
+// toolchainSwitchMsg matches the notices the go command prints when
+// GOTOOLCHAIN lets it download or switch to a newer toolchain.
+var toolchainSwitchMsg = regexp.MustCompile(`(?:go: downloading|switching to) (go\d+\.\d+\S*)`)
+
+// toolchainSwitch reports the toolchain the go command switched to, if
+// its output o says it did.
+func toolchainSwitch(o string) (string, bool) {
+	m := toolchainSwitchMsg.FindStringSubmatch(o)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// upstreamServerErr matches 5xx responses as reported
+// by the go command and by git.
+var upstreamServerErr = regexp.MustCompile(`returned error: 5\d\d|\b5\d\d (Internal Server Error|Bad Gateway|Service Unavailable|Gateway Timeout)`)
+
+func isNetworkFlake(o string) bool {
+	for _, msg := range networkFlakeMessages {
+		if strings.Contains(o, msg) {
+			return true
+		}
+	}
+	return upstreamServerErr.MatchString(o)
+}
+
+// cacheLockErr matches the error the go command reports when locking a
+// file in the module cache fails with EAGAIN because another process holds
+// the lock, as happens on filesystems without blocking locks, such as NFS.
+// Other errors on lock files, e.g. permission problems, are not transient.
+var cacheLockErr = regexp.MustCompile(`\.lock: resource temporarily unavailable`)
+
+// isCacheLocked reports whether o indicates that go mod download failed
+// because of contention on a shared module cache. Such failures are
+// transient and succeed once the other go process releases the lock.
+func isCacheLocked(o string) bool {
+	return cacheLockErr.MatchString(o)
 }
 
-// getRepoDirName takes a raw repository URI and a version and creates a directory name that the
-// repository contents can be put into.
+// getRepoDirName takes a module path and a version and creates a directory path that the
+// repository contents can be put into. It follows the layout of the module cache, so that
+// modules whose paths differ only in case or in slashes never share a directory, even on
+// case-insensitive filesystems.
 func getRepoDirName(repoURI, version string) string {
-	escapedURI := strings.ReplaceAll(repoURI, "/", "-")
-	return fmt.Sprintf("%s-%s", escapedURI, version)
+	// both have been checked by checkModVer, which makes escaping them infallible
+	escapedURI, _ := module.EscapePath(repoURI)
+	escapedVer, _ := module.EscapeVersion(version)
+	return filepath.FromSlash(escapedURI) + "@" + escapedVer
 }
 
 func validGoBinary(name string) error {
@@ -0,0 +1,227 @@
+package module
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gomods/athens/pkg/log"
+)
+
+// FetcherOption configures optional behavior of the
+// Fetcher returned by NewGoGetFetcher.
+type FetcherOption func(*goGetFetcher)
+
+// WithCABundle makes the go command and the VCS tools it
+// spawns trust the certificates in the PEM bundle at path.
+// This is needed to fetch from internal git hosts whose
+// certificates are signed by a private CA.
+// The bundle replaces the system trust store rather than adding
+// to it, so path must be a full bundle: the system roots with the
+// private CA appended, e.g. the output of
+// cat /etc/ssl/certs/ca-certificates.crt internal-ca.pem.
+// A bundle holding only the private CA breaks fetches from public
+// hosts such as github.com and proxy.golang.org.
+// Variables set explicitly in the fetcher's envVars take precedence.
+func WithCABundle(path string) FetcherOption {
+	return func(g *goGetFetcher) {
+		caEnv := []string{
+			"GIT_SSL_CAINFO=" + path,
+			"SSL_CERT_FILE=" + path,
+		}
+		g.envVars = append(caEnv, g.envVars...)
+	}
+}
+
+// WithSSH makes git authenticate SSH remotes with the private key at
+// keyPath. If knownHostsPath is not empty, host keys are strictly checked
+// against that file. Pair it with GOPRIVATE and a git insteadOf rule to
+// fetch modules only reachable through git@host:org/repo.git remotes.
+// A GIT_SSH_COMMAND set explicitly in the fetcher's envVars takes precedence.
+func WithSSH(keyPath, knownHostsPath string) FetcherOption {
+	return func(g *goGetFetcher) {
+		sshCmd := "ssh -i " + shellQuote(keyPath) + " -o IdentitiesOnly=yes"
+		if knownHostsPath != "" {
+			sshCmd += " -o StrictHostKeyChecking=yes -o UserKnownHostsFile=" + shellQuote(knownHostsPath)
+		}
+		g.envVars = append([]string{"GIT_SSH_COMMAND=" + sshCmd}, g.envVars...)
+	}
+}
+
+// shellQuote quotes s so that git passes it to ssh as a single argument.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// WithMaxZipSize makes Fetch reject modules whose zip is larger
+// than maxBytes with a KindTooLarge error. A value of zero or
+// less means there is no limit.
+func WithMaxZipSize(maxBytes int64) FetcherOption {
+	return func(g *goGetFetcher) {
+		g.maxZipSize = maxBytes
+	}
+}
+
+// WithCommandRecorder makes the fetcher report every go command it
+// runs, along with its environment, to rec and to the active trace span.
+// Values of environment variables that look like secrets are redacted,
+// see WithRedactedEnv.
+func WithCommandRecorder(rec CommandRecorder) FetcherOption {
+	return func(g *goGetFetcher) {
+		g.recorder = rec
+	}
+}
+
+// WithRedactedEnv replaces the list of environment variable name
+// fragments whose values are redacted before a go command is recorded.
+// Matching is case insensitive.
+func WithRedactedEnv(keys ...string) FetcherOption {
+	return func(g *goGetFetcher) {
+		g.redactKeys = keys
+	}
+}
+
+// WithStaleCleanup makes NewGoGetFetcher remove temporary GOPATHs
+// that were left behind in the fetcher's directory by a process that
+// was killed mid-fetch, along with any module cache locks in them.
+// Only GOPATHs in which nothing has been modified for at least maxAge
+// are removed, so that fetches in flight in another process sharing
+// the directory are left alone. The cleanup is best effort: GOPATHs
+// that cannot be inspected or removed, e.g. because another user owns
+// them, are reported to lggr and skipped.
+func WithStaleCleanup(maxAge time.Duration, lggr log.Entry) FetcherOption {
+	return func(g *goGetFetcher) {
+		g.staleAge = maxAge
+		g.staleLog = lggr
+	}
+}
+
+// WithTraceIDInErrors makes Fetch append the ID of the active trace to the
+// errors it returns, so that an error reported by a client can be matched
+// with the server side trace and logs. See TraceID.
+func WithTraceIDInErrors() FetcherOption {
+	return func(g *goGetFetcher) {
+		g.traceErrors = true
+	}
+}
+
+// WithSourceTransform makes Fetch run t over a copy of the module's source
+// tree and serve a zip built from the transformed tree, for example to add a
+// NOTICE file or strip files that must not be mirrored.
+func WithSourceTransform(t SourceTransform) FetcherOption {
+	return func(g *goGetFetcher) {
+		g.transform = t
+	}
+}
+
+// WithRetry makes the fetcher retry go mod download up to retries times
+// when it fails for a transient reason, such as a network timeout or a 5xx
+// response from upstream. The first retry happens after delay, and the
+// delay doubles on every subsequent attempt. Modules that do not exist are
+// never retried.
+func WithRetry(retries int, delay time.Duration) FetcherOption {
+	return func(g *goGetFetcher) {
+		g.retries = retries
+		g.retryDelay = delay
+	}
+}
+
+// WithModulePathCheck makes Fetch reject modules whose go.mod does not
+// declare the path they were fetched as. The go command refuses to use
+// such modules as dependencies, so serving them only defers the failure
+// to the client's build.
+func WithModulePathCheck() FetcherOption {
+	return func(g *goGetFetcher) {
+		g.checkModPath = true
+	}
+}
+
+// WithDownloadTimeout bounds how long a single go mod download may run,
+// regardless of the deadline of the context passed to Fetch. A download
+// that runs out of time fails with a KindTimeout error.
+func WithDownloadTimeout(d time.Duration) FetcherOption {
+	return func(g *goGetFetcher) {
+		g.downloadTimeout = d
+	}
+}
+
+// WithProxies makes the fetcher download modules from the given GOPROXY
+// URLs, in order, overriding any GOPROXY in the fetcher's envVars. A module
+// that is not found (404 or 410) in one proxy is looked up in the next one;
+// any other failure ends the lookup. The error of the last attempt is the
+// one reported. Include "direct" to fall back to the VCS. Without any URLs
+// the option is ignored, rather than leaving GOPROXY empty, which the go
+// command takes to mean proxy.golang.org.
+func WithProxies(urls ...string) FetcherOption {
+	return func(g *goGetFetcher) {
+		if len(urls) == 0 {
+			return
+		}
+		// clip envVars so that appending never writes to the caller's array
+		envVars := g.envVars[:len(g.envVars):len(g.envVars)]
+		g.envVars = append(envVars, "GOPROXY="+strings.Join(urls, ","))
+	}
+}
+
+// RepoDirNamer returns the directory, relative to the src directory of a
+// fetch's temporary GOPATH, that the go command is run from for mod@ver.
+// The returned path may contain separators to nest directories.
+type RepoDirNamer func(mod, ver string) string
+
+// WithRepoDirName overrides how the fetcher lays out the working directory
+// of each download inside its temporary GOPATH, which can make the temp
+// area easier to inspect. By default it follows the layout of the module
+// cache, <escaped module path>@<escaped version>.
+func WithRepoDirName(f RepoDirNamer) FetcherOption {
+	return func(g *goGetFetcher) {
+		g.repoDirName = f
+	}
+}
+
+// WithTreeHash makes the fetcher record the dirhash of each module's
+// extracted source tree in Version.TreeHash, so that auditors can compare
+// it with the checksum of the zip that is served. The hash is computed
+// before any SourceTransform runs.
+func WithTreeHash() FetcherOption {
+	return func(g *goGetFetcher) {
+		g.treeHash = true
+	}
+}
+
+// WithVersionCheck makes the fetcher refuse modules whose .info file or zip
+// disagree with the version the go command reported, which is the version
+// they are stored under. Such disagreements usually concern the
+// +incompatible suffix and make clients fail checksum verification.
+func WithVersionCheck() FetcherOption {
+	return func(g *goGetFetcher) {
+		g.checkVersions = true
+	}
+}
+
+// WithInMemoryZip makes the fetcher read module zips of up to maxSize bytes
+// into memory and remove the temporary GOPATH before Fetch returns. Such
+// zips implement io.ReadSeeker and have a Size() int64 method reporting
+// their length, which saves callers that upload them a copy to disk.
+// Bigger zips are still read from disk.
+func WithInMemoryZip(maxSize int64) FetcherOption {
+	return func(g *goGetFetcher) {
+		g.memZipMax = maxSize
+	}
+}
+
+// WithClientMessages replaces the message of fetch errors of the given
+// kinds with a fixed one, e.g. errors.KindRateLimit: "upstream busy, retry
+// later", so that go command and VCS output does not reach clients. The
+// original error is logged and recorded on the fetch span. The kind of
+// the returned error is unchanged.
+func WithClientMessages(msgs map[int]string) FetcherOption {
+	return func(g *goGetFetcher) {
+		g.clientMessages = msgs
+	}
+}
+
+// WithFetchObserver reports the outcome and duration of every fetch to o.
+func WithFetchObserver(o FetchObserver) FetcherOption {
+	return func(g *goGetFetcher) {
+		g.observer = o
+	}
+}
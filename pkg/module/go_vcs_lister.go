@@ -68,6 +68,20 @@ func (l *vcsLister) List(ctx context.Context, module string) (*storage.RevInfo,
 	err = cmd.Run()
 	if err != nil {
 		err = fmt.Errorf("%w: %s", err, stderr)
+		if isTransient(stderr.String()) {
+			// the go command may have listed some versions before
+			// failing. Hand them back with the error so that the caller
+			// can decide whether to use them or to retry.
+			var lr listResp
+			if jsonErr := json.NewDecoder(stdout).Decode(&lr); jsonErr == nil {
+				rev := storage.RevInfo{
+					Time:    lr.Time,
+					Version: lr.Version,
+				}
+				return &rev, lr.Versions, errors.E(op, err, errors.KindServiceUnavailable)
+			}
+			return nil, nil, errors.E(op, err, errors.KindServiceUnavailable)
+		}
 		// as of now, we can't recognize between a true NotFound
 		// and an unexpected error, so we choose the more
 		// hopeful path of NotFound. This way the Go command
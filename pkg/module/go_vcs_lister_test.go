@@ -0,0 +1,34 @@
+package module
+
+import (
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+func (s *ModuleSuite) TestVCSListerPartialResults() {
+	r := s.Require()
+	goBin := fakeGoBinary(s.T(), `echo '{"Path":"mockmod.xyz","Version":"v1.1.0","Versions":["v1.0.0","v1.1.0"]}'
+echo 'go: mockmod.xyz: unexpected EOF' >&2
+exit 1`)
+	lister := NewVCSLister(goBin, s.env, afero.NewOsFs())
+
+	rev, versions, err := lister.List(ctx, "mockmod.xyz")
+	r.Error(err)
+	r.Equal(errors.KindServiceUnavailable, errors.Kind(err))
+	r.Equal([]string{"v1.0.0", "v1.1.0"}, versions)
+	r.Equal("v1.1.0", rev.Version)
+}
+
+func (s *ModuleSuite) TestVCSListerNotFound() {
+	r := s.Require()
+	goBin := fakeGoBinary(s.T(), `echo '{"Path":"mockmod.xyz","Versions":["v1.0.0"]}'
+echo 'go: mockmod.xyz: no matching versions' >&2
+exit 1`)
+	lister := NewVCSLister(goBin, s.env, afero.NewOsFs())
+
+	rev, versions, err := lister.List(ctx, "mockmod.xyz")
+	r.Error(err)
+	r.Equal(errors.KindNotFound, errors.Kind(err))
+	r.Nil(versions)
+	r.Nil(rev)
+}
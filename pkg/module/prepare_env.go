@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 )
 
 // prepareEnv will return all the appropriate
@@ -50,7 +51,15 @@ func prepareEnv(gopath string, envVars []string) []string {
 			cmdEnv = append(cmdEnv, fmt.Sprintf("%s=%s", key, v))
 		}
 	}
-	cmdEnv = append(cmdEnv, envVars...)
+	for _, kv := range envVars {
+		// the temporary GOPATH and GOCACHE isolate fetches from each other
+		// and from the host, so they cannot be overridden.
+		if key, _, _ := strings.Cut(kv, "="); key == "GOPATH" || key == "GOCACHE" {
+			continue
+		}
+		cmdEnv = append(cmdEnv, kv)
+	}
+	cmdEnv = append(cmdEnv, goFlags(envVars))
 
 	if sshAuthSockVal, hasSSHAuthSock := os.LookupEnv("SSH_AUTH_SOCK"); hasSSHAuthSock {
 		// Verify that the ssh agent unix socket exists and is a unix socket.
@@ -60,5 +69,37 @@ func prepareEnv(gopath string, envVars []string) []string {
 			cmdEnv = append(cmdEnv, sshAuthSock)
 		}
 	}
-	return cmdEnv
+	return dedupEnv(cmdEnv)
+}
+
+// dedupEnv returns env without the variables that are set again later in it,
+// so that every variable is listed once, with the value that takes effect.
+func dedupEnv(env []string) []string {
+	last := make(map[string]int, len(env))
+	for i, kv := range env {
+		key, _, _ := strings.Cut(kv, "=")
+		last[key] = i
+	}
+	res := make([]string, 0, len(last))
+	for i, kv := range env {
+		key, _, _ := strings.Cut(kv, "=")
+		if last[key] == i {
+			res = append(res, kv)
+		}
+	}
+	return res
+}
+
+// goFlags returns the GOFLAGS the go command runs with: the last GOFLAGS in
+// envVars, if any, plus -modcacherw. The go command makes the module cache
+// read-only by default, which gets in the way of removing the temporary
+// GOPATHs it is written to.
+func goFlags(envVars []string) string {
+	var flags string
+	for _, kv := range envVars {
+		if v, ok := strings.CutPrefix(kv, "GOFLAGS="); ok {
+			flags = v
+		}
+	}
+	return "GOFLAGS=" + strings.TrimSpace(flags+" -modcacherw")
 }
@@ -0,0 +1,35 @@
+package module
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+func (s *ModuleSuite) TestPrepareEnvGoFlags() {
+	r := s.Require()
+	r.Contains(prepareEnv("/gopath", nil), "GOFLAGS=-modcacherw")
+
+	env := prepareEnv("/gopath", []string{"GOFLAGS=-mod=mod", "GOFLAGS=-insecure -mod=mod"})
+	r.Contains(env, "GOFLAGS=-insecure -mod=mod -modcacherw")
+}
+
+func (s *ModuleSuite) TestPrepareEnvIsolation() {
+	r := s.Require()
+	env := prepareEnv("/gopath", []string{
+		"GOPATH=/home/athens/go",
+		"GOCACHE=/home/athens/.cache/go-build",
+		"GOPROXY=https://proxy.example",
+		"GONOSUMDB=mockmod.xyz",
+		"GOPROXY=direct",
+	})
+
+	vars := map[string][]string{}
+	for _, kv := range env {
+		key, val, _ := strings.Cut(kv, "=")
+		vars[key] = append(vars[key], val)
+	}
+	r.Equal([]string{"/gopath"}, vars["GOPATH"])
+	r.Equal([]string{filepath.Join("/gopath", "cache")}, vars["GOCACHE"])
+	r.Equal([]string{"direct"}, vars["GOPROXY"], "expected later values to win")
+	r.Equal([]string{"mockmod.xyz"}, vars["GONOSUMDB"])
+}
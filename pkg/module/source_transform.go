@@ -0,0 +1,189 @@
+package module
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/spf13/afero"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb/dirhash"
+	modzip "golang.org/x/mod/zip"
+)
+
+// SourceTransform modifies the source tree of mod@ver, rooted at dir in fs,
+// before it is packaged into the module zip. It may add, remove or modify
+// files. The go.mod served for the module is not affected by changes made
+// to the go.mod file in dir, so transforms should leave it alone.
+type SourceTransform func(ctx context.Context, fs afero.Fs, mod, ver, dir string) error
+
+// transformSource copies the module sources the go command extracted into
+// a writable directory under gopath, runs the fetcher's SourceTransform over
+// them and packages the result into a new zip. It returns the path of the new zip.
+func (g *goGetFetcher) transformSource(ctx context.Context, m GoModule, gopath string) (string, error) {
+	const op errors.Op = "goGetFetcher.transformSource"
+	if m.Dir == "" {
+		return "", errors.E(op, errors.M(m.Path), errors.V(m.Version), "go mod download did not return a source directory to transform")
+	}
+	dir := filepath.Join(gopath, "transform")
+	if err := g.copyDir(m.Dir, dir); err != nil {
+		return "", errors.E(op, err)
+	}
+	if err := g.transform(ctx, g.fs, m.Path, m.Version, dir); err != nil {
+		return "", errors.E(op, err)
+	}
+
+	var files []modzip.File
+	err := afero.Walk(g.fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, aferoFile{fs: g.fs, path: path, slashPath: filepath.ToSlash(rel)})
+		return nil
+	})
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+
+	zipPath := filepath.Join(gopath, "transform.zip")
+	zf, err := g.fs.Create(zipPath)
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	if err := modzip.Create(zf, module.Version{Path: m.Path, Version: m.Version}, files); err != nil {
+		_ = zf.Close()
+		return "", errors.E(op, err)
+	}
+	// a failed close can leave the zip truncated, it must not be hashed or served
+	if err := zf.Close(); err != nil {
+		return "", errors.E(op, err)
+	}
+	return zipPath, nil
+}
+
+// hashZip computes the go.sum checksum of the module zip at path in fs.
+func hashZip(fs afero.Fs, path string) (string, error) {
+	const op errors.Op = "module.hashZip"
+	f, err := fs.Open(path)
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	defer func() { _ = f.Close() }()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	zr, err := zip.NewReader(f, fi.Size())
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	files := make([]string, 0, len(zr.File))
+	zfiles := make(map[string]*zip.File, len(zr.File))
+	for _, zf := range zr.File {
+		files = append(files, zf.Name)
+		zfiles[zf.Name] = zf
+	}
+	sum, err := dirhash.Hash1(files, func(name string) (io.ReadCloser, error) {
+		return zfiles[name].Open()
+	})
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	return sum, nil
+}
+
+// copyDir recursively copies the contents of src into dst. File permission
+// bits are preserved, except that copies are always writable by their owner:
+// the module cache is read-only and the copies are meant to be modified.
+func (g *goGetFetcher) copyDir(src, dst string) error {
+	const op errors.Op = "goGetFetcher.copyDir"
+	err := afero.Walk(g.fs, src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return g.fs.MkdirAll(target, os.ModeDir|os.ModePerm)
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if copied, err := g.copySymlink(path, target); copied || err != nil {
+				return err
+			}
+		}
+		if err := g.copyFile(path, target); err != nil {
+			return err
+		}
+		return g.fs.Chmod(target, info.Mode().Perm()|0o200)
+	})
+	if err != nil {
+		return errors.E(op, err)
+	}
+	return nil
+}
+
+// copySymlink recreates the symlink src at dst, pointing at the same target.
+// It reports false if the fetcher's filesystem does not support symlinks,
+// in which case the caller should copy the link's target instead.
+func (g *goGetFetcher) copySymlink(src, dst string) (bool, error) {
+	reader, ok := g.fs.(afero.LinkReader)
+	if !ok {
+		return false, nil
+	}
+	linker, ok := g.fs.(afero.Linker)
+	if !ok {
+		return false, nil
+	}
+	target, err := reader.ReadlinkIfPossible(src)
+	if err != nil {
+		return false, err
+	}
+	return true, linker.SymlinkIfPossible(target, dst)
+}
+
+func (g *goGetFetcher) copyFile(src, dst string) error {
+	in, err := g.fs.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := g.fs.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// aferoFile implements the zip.File interface on top of an afero.Fs.
+type aferoFile struct {
+	fs        afero.Fs
+	path      string
+	slashPath string
+}
+
+func (f aferoFile) Path() string                 { return f.slashPath }
+func (f aferoFile) Open() (io.ReadCloser, error) { return f.fs.Open(f.path) }
+
+// Lstat does not follow symlinks where the fs supports them, so that
+// modzip.Create leaves them out of the zip just as the go command does.
+func (f aferoFile) Lstat() (os.FileInfo, error) {
+	if lstater, ok := f.fs.(afero.Lstater); ok {
+		fi, _, err := lstater.LstatIfPossible(f.path)
+		return fi, err
+	}
+	return f.fs.Stat(f.path)
+}
@@ -0,0 +1,59 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/spf13/afero"
+)
+
+func (s *ModuleSuite) TestCopyDirPreservesModes() {
+	r := s.Require()
+	g := &goGetFetcher{fs: afero.NewMemMapFs()}
+	src := filepath.Join("/gopath", "pkg", "mod", "mockmod.xyz@v1.2.3")
+	r.NoError(createAndWriteFile(g.fs, filepath.Join(src, "scripts", "gen.sh"), "#!/bin/sh"))
+	r.NoError(g.fs.Chmod(filepath.Join(src, "scripts", "gen.sh"), 0o755))
+	r.NoError(createAndWriteFile(g.fs, filepath.Join(src, "go.mod"), "module mockmod.xyz"))
+	r.NoError(g.fs.Chmod(filepath.Join(src, "go.mod"), 0o444))
+
+	dst := "/transform"
+	r.NoError(g.copyDir(src, dst))
+
+	fi, err := g.fs.Stat(filepath.Join(dst, "scripts", "gen.sh"))
+	r.NoError(err)
+	r.Equal(os.FileMode(0o755), fi.Mode().Perm())
+	fi, err = g.fs.Stat(filepath.Join(dst, "go.mod"))
+	r.NoError(err)
+	r.Equal(os.FileMode(0o644), fi.Mode().Perm(), "expected read-only files to become writable by their owner")
+	content, err := afero.ReadFile(g.fs, filepath.Join(dst, "scripts", "gen.sh"))
+	r.NoError(err)
+	r.Equal("#!/bin/sh", string(content))
+}
+
+func (s *ModuleSuite) TestCopyDirSymlinks() {
+	if runtime.GOOS == "windows" {
+		s.T().Skip("creating symlinks requires elevated privileges on windows")
+	}
+	r := s.Require()
+	// symlinks need a filesystem that supports them
+	g := &goGetFetcher{fs: afero.NewOsFs()}
+	src := filepath.Join(s.T().TempDir(), "mockmod.xyz@v1.2.3")
+	r.NoError(os.MkdirAll(filepath.Join(src, "testdata"), 0o755))
+	r.NoError(createAndWriteFile(g.fs, filepath.Join(src, "testdata", "golden.txt"), "golden"))
+	r.NoError(os.Symlink(filepath.Join("testdata", "golden.txt"), filepath.Join(src, "golden.txt")))
+	r.NoError(os.Symlink("missing.txt", filepath.Join(src, "dangling.txt")))
+
+	dst := filepath.Join(s.T().TempDir(), "transform")
+	r.NoError(g.copyDir(src, dst))
+
+	target, err := os.Readlink(filepath.Join(dst, "golden.txt"))
+	r.NoError(err)
+	r.Equal(filepath.Join("testdata", "golden.txt"), target)
+	content, err := os.ReadFile(filepath.Join(dst, "golden.txt"))
+	r.NoError(err)
+	r.Equal("golden", string(content))
+	target, err = os.Readlink(filepath.Join(dst, "dangling.txt"))
+	r.NoError(err)
+	r.Equal("missing.txt", target)
+}
@@ -0,0 +1,41 @@
+package module
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gomods/athens/pkg/errors"
+	"go.opencensus.io/trace"
+)
+
+// tracedError annotates an error with the trace it happened in.
+type tracedError struct {
+	err     error
+	traceID string
+}
+
+func (e *tracedError) Error() string {
+	return fmt.Sprintf("%v (trace ID: %s)", e.err, e.traceID)
+}
+
+func (e *tracedError) Unwrap() error { return e.err }
+
+// withTraceID annotates err with the ID of the trace active in ctx,
+// if there is one.
+func withTraceID(ctx context.Context, err error) error {
+	span := trace.FromContext(ctx)
+	if span == nil {
+		return err
+	}
+	return &tracedError{err: err, traceID: span.SpanContext().TraceID.String()}
+}
+
+// TraceID returns the ID of the trace a fetch error happened in,
+// or an empty string if the error was not annotated with one.
+func TraceID(err error) string {
+	var te *tracedError
+	if !errors.AsErr(err, &te) {
+		return ""
+	}
+	return te.traceID
+}
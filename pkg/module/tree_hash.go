@@ -0,0 +1,47 @@
+package module
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/spf13/afero"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// hashTree computes the dirhash of the source tree of m, the same way
+// dirhash.HashDir does but reading through fs. For an untouched tree it
+// matches the go.sum checksum of the module zip.
+func hashTree(fs afero.Fs, m GoModule) (string, error) {
+	const op errors.Op = "module.hashTree"
+	if m.Dir == "" {
+		return "", errors.E(op, errors.M(m.Path), errors.V(m.Version), "go mod download did not return a source directory to hash", errors.KindUnexpected)
+	}
+	prefix := m.Path + "@" + m.Version
+	paths := map[string]string{}
+	var files []string
+	err := afero.Walk(fs, m.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(m.Dir, path)
+		if err != nil {
+			return err
+		}
+		name := prefix + "/" + filepath.ToSlash(rel)
+		paths[name] = path
+		files = append(files, name)
+		return nil
+	})
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	sum, err := dirhash.Hash1(files, func(name string) (io.ReadCloser, error) {
+		return fs.Open(paths[name])
+	})
+	if err != nil {
+		return "", errors.E(op, err)
+	}
+	return sum, nil
+}
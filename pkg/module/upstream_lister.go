@@ -8,6 +8,10 @@ import (
 
 // UpstreamLister retrieves a list of available module versions from upstream
 // i.e. VCS, and a Storage backend.
+//
+// When listing fails with a transient (KindServiceUnavailable) error, List
+// may also return the versions it found before failing. Callers that need a
+// complete list should treat them as partial.
 type UpstreamLister interface {
 	List(ctx context.Context, mod string) (*storage.RevInfo, []string, error)
 }
@@ -0,0 +1,54 @@
+package module
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+// checkVersionConsistency returns an error unless the .info file and every
+// file in the zip of m agree with m.Version, which becomes the Semver of the
+// stored version. This mostly matters for +incompatible versions, where a
+// missing or extra suffix in any of them makes clients fail verification.
+func checkVersionConsistency(fs afero.Fs, m GoModule, info []byte) error {
+	const op errors.Op = "module.checkVersionConsistency"
+	mismatch := func(what, got string) error {
+		msg := fmt.Sprintf("%s has version %q but %s@%s was downloaded", what, got, m.Path, m.Version)
+		return errors.E(op, errors.M(m.Path), errors.V(m.Version), msg, errors.KindUnexpected)
+	}
+
+	var i struct{ Version string }
+	if err := json.Unmarshal(info, &i); err != nil {
+		return errors.E(op, errors.M(m.Path), errors.V(m.Version), err)
+	}
+	if i.Version != m.Version {
+		return mismatch("the .info file", i.Version)
+	}
+
+	f, err := fs.Open(m.Zip)
+	if err != nil {
+		return errors.E(op, err)
+	}
+	defer func() { _ = f.Close() }()
+	fi, err := f.Stat()
+	if err != nil {
+		return errors.E(op, err)
+	}
+	zr, err := zip.NewReader(f, fi.Size())
+	if err != nil {
+		return errors.E(op, errors.M(m.Path), errors.V(m.Version), err)
+	}
+	prefix := m.Path + "@" + m.Version + "/"
+	for _, zf := range zr.File {
+		if !strings.HasPrefix(zf.Name, prefix) {
+			root, _, _ := strings.Cut(zf.Name, "/")
+			_, ver, _ := strings.Cut(root, "@")
+			return mismatch(fmt.Sprintf("zip entry %q", zf.Name), ver)
+		}
+	}
+	return nil
+}
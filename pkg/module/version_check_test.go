@@ -0,0 +1,101 @@
+package module
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gomods/athens/pkg/errors"
+	"github.com/spf13/afero"
+)
+
+func (s *ModuleSuite) TestCheckVersionConsistency() {
+	const (
+		mod = "mockmod.xyz"
+		ver = "v2.0.0+incompatible"
+	)
+	tests := []struct {
+		name      string
+		info      string
+		zipPrefix string
+		err       string
+	}{
+		{
+			name:      "consistent",
+			info:      `{"Version":"v2.0.0+incompatible"}`,
+			zipPrefix: "mockmod.xyz@v2.0.0+incompatible/",
+		},
+		{
+			name:      "info without suffix",
+			info:      `{"Version":"v2.0.0"}`,
+			zipPrefix: "mockmod.xyz@v2.0.0+incompatible/",
+			err:       `the .info file has version "v2.0.0" but mockmod.xyz@v2.0.0+incompatible was downloaded`,
+		},
+		{
+			name:      "zip without suffix",
+			info:      `{"Version":"v2.0.0+incompatible"}`,
+			zipPrefix: "mockmod.xyz@v2.0.0/",
+			err:       `zip entry "mockmod.xyz@v2.0.0/go.mod" has version "v2.0.0" but mockmod.xyz@v2.0.0+incompatible was downloaded`,
+		},
+	}
+	for _, tc := range tests {
+		s.Run(tc.name, func() {
+			r := s.Require()
+			var buf bytes.Buffer
+			zw := zip.NewWriter(&buf)
+			w, err := zw.Create(tc.zipPrefix + "go.mod")
+			r.NoError(err)
+			_, err = w.Write([]byte("module " + mod))
+			r.NoError(err)
+			r.NoError(zw.Close())
+			fs := afero.NewMemMapFs()
+			r.NoError(afero.WriteFile(fs, "/v.zip", buf.Bytes(), 0o644))
+
+			m := GoModule{Path: mod, Version: ver, Zip: "/v.zip"}
+			err = checkVersionConsistency(fs, m, []byte(tc.info))
+			if tc.err == "" {
+				r.NoError(err)
+				return
+			}
+			r.EqualError(err, tc.err)
+			r.Equal(errors.KindUnexpected, errors.Kind(err))
+		})
+	}
+}
+
+func (s *ModuleSuite) TestGoGetFetcherVersionCheck() {
+	r := s.Require()
+	const ver = "v2.0.0+incompatible"
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("mockmod.xyz@" + ver + "/mod.go")
+	r.NoError(err)
+	_, err = w.Write([]byte("package mod"))
+	r.NoError(err)
+	r.NoError(zw.Close())
+	zipFile := filepath.Join(s.T().TempDir(), "mod.zip")
+	r.NoError(os.WriteFile(zipFile, buf.Bytes(), 0o644))
+
+	goBin := fakeGoBinary(s.T(), fakeDownload("mockmod.xyz", ver)+`
+cp '`+zipFile+`' "$dir/`+ver+`.zip"`)
+	fetcher, err := NewGoGetFetcher(goBin, "", s.env, afero.NewOsFs(), WithVersionCheck())
+	r.NoError(err)
+
+	v, err := fetcher.Fetch(ctx, "mockmod.xyz", ver)
+	r.NoError(err)
+	defer v.Zip.Close()
+	r.Equal(ver, v.Semver)
+	var info struct{ Version string }
+	r.NoError(json.Unmarshal(v.Info, &info))
+	r.Equal(ver, info.Version)
+	zipBytes, err := io.ReadAll(v.Zip)
+	r.NoError(err)
+	zr, err := zip.NewReader(bytes.NewReader(zipBytes), int64(len(zipBytes)))
+	r.NoError(err)
+	for _, f := range zr.File {
+		r.Equal("mockmod.xyz@"+ver+"/mod.go", f.Name)
+	}
+}
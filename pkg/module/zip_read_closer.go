@@ -1,10 +1,16 @@
 package module
 
 import (
+	"bytes"
+	goerrors "errors"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/gomods/athens/pkg/errors"
+	"github.com/gomods/athens/pkg/log"
 	"github.com/spf13/afero"
 )
 
@@ -12,8 +18,15 @@ type zipReadCloser struct {
 	zip    io.ReadCloser
 	fs     afero.Fs
 	goPath string
+	// touched is when Read last refreshed the mtime of goPath.
+	touched time.Time
 }
 
+// goPathTouchInterval is how often a zip being read refreshes the mtime
+// of its GOPATH, so that clearStaleGoPaths does not take a slow stream
+// for an abandoned fetch.
+const goPathTouchInterval = time.Minute
+
 // Close closes the zip file handle and clears up disk space used by the underlying disk ref.
 // It is the caller's responsibility to call this method to free up utilized disk space.
 func (rc *zipReadCloser) Close() error {
@@ -22,9 +35,24 @@ func (rc *zipReadCloser) Close() error {
 }
 
 func (rc *zipReadCloser) Read(p []byte) (n int, err error) {
+	if time.Since(rc.touched) >= goPathTouchInterval {
+		rc.touched = time.Now()
+		_ = rc.fs.Chtimes(rc.goPath, rc.touched, rc.touched)
+	}
 	return rc.zip.Read(p)
 }
 
+// memZip is a module zip read into memory. Besides io.ReadCloser it
+// implements io.ReadSeeker, and Size reports the length of the zip.
+type memZip struct {
+	*bytes.Reader
+}
+
+// Close is a no-op, the disk space backing the zip was freed when it was read.
+func (memZip) Close() error {
+	return nil
+}
+
 // clearFiles deletes all data from the given fs at path root.
 // This function must be called when zip is closed to cleanup the entire GOPATH created by the diskref.
 func clearFiles(fs afero.Fs, root string) error {
@@ -48,3 +76,74 @@ func clearFiles(fs afero.Fs, root string) error {
 	}
 	return nil
 }
+
+// goPathPrefix is the name prefix of the temporary GOPATHs created by the
+// fetcher. It sets them apart from the ones the VCS lister creates in the
+// same directory, which clearStaleGoPaths must leave alone.
+const goPathPrefix = "athens-goget"
+
+// errRecent stops the walk in modifiedSince at the first
+// file modified after the cutoff.
+var errRecent = goerrors.New("recently modified")
+
+// clearStaleGoPaths removes the temporary GOPATHs under dir in which no
+// file or directory has been modified for at least maxAge. Fetches in
+// progress keep their GOPATH fresh since the go command writes to it
+// while downloading and reading the returned zip refreshes it, see
+// zipReadCloser.Read. An empty dir means the default temporary
+// directory, matching afero.TempDir. GOPATHs that cannot be inspected
+// or removed are reported to lggr, if not nil, and skipped; only
+// failing to read dir itself is an error.
+func clearStaleGoPaths(fs afero.Fs, dir string, maxAge time.Duration, lggr log.Entry) error {
+	const op errors.Op = "module.clearStaleGoPaths"
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if lggr == nil {
+		lggr = log.NoOpLogger()
+	}
+	entries, err := afero.ReadDir(fs, dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.E(op, err)
+	}
+	cutoff := time.Now().Add(-maxAge)
+	for _, fi := range entries {
+		if !fi.IsDir() || !strings.HasPrefix(fi.Name(), goPathPrefix) {
+			continue
+		}
+		goPath := filepath.Join(dir, fi.Name())
+		recent, err := modifiedSince(fs, goPath, cutoff)
+		if err == nil && !recent {
+			err = clearFiles(fs, goPath)
+		}
+		if err != nil {
+			lggr.WithFields(map[string]any{"gopath": goPath}).Warnf("could not clear stale GOPATH %s: %v", goPath, err)
+		}
+	}
+	return nil
+}
+
+// modifiedSince reports whether anything in the tree at root
+// was modified after cutoff.
+func modifiedSince(fs afero.Fs, root string, cutoff time.Time) (bool, error) {
+	const op errors.Op = "module.modifiedSince"
+	err := afero.Walk(fs, root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(cutoff) {
+			return errRecent
+		}
+		return nil
+	})
+	if errors.IsErr(err, errRecent) {
+		return true, nil
+	}
+	if err != nil {
+		return false, errors.E(op, err)
+	}
+	return false, nil
+}
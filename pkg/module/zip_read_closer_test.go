@@ -1,8 +1,14 @@
 package module
 
 import (
+	"bytes"
+	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/gomods/athens/pkg/log"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 )
 
@@ -41,6 +47,115 @@ func (m *ModuleSuite) TestZipReadCloser() {
 	r.NotNil(err)
 }
 
+func (m *ModuleSuite) TestClearStaleGoPaths() {
+	r := m.Require()
+	fs := afero.NewMemMapFs()
+	const dir = "/goget"
+	old := time.Now().Add(-2 * time.Hour)
+	age := func(root string) {
+		r.NoError(afero.Walk(fs, root, func(path string, _ os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			return fs.Chtimes(path, old, old)
+		}))
+	}
+
+	stale := filepath.Join(dir, "athens-goget123")
+	lock := filepath.Join(stale, "pkg", "mod", "cache", "download", "mockmod.xyz", "@v", "v1.2.3.lock")
+	r.NoError(createAndWriteFile(fs, lock, ""))
+	age(stale)
+
+	fresh := filepath.Join(dir, "athens-goget456")
+	r.NoError(fs.MkdirAll(fresh, 0o755))
+
+	// a long fetch only writes deep in the tree
+	busy := filepath.Join(dir, "athens-goget789")
+	r.NoError(createAndWriteFile(fs, filepath.Join(busy, "pkg", "mod", "mockmod.xyz@v1.2.3.zip"), ""))
+	r.NoError(fs.Chtimes(busy, old, old))
+
+	// GOPATHs of the VCS lister share the default temporary directory
+	lister := filepath.Join(dir, "athens123")
+	r.NoError(fs.MkdirAll(lister, 0o755))
+	age(lister)
+
+	other := filepath.Join(dir, "unrelated")
+	r.NoError(fs.MkdirAll(other, 0o755))
+	age(other)
+
+	r.NoError(clearStaleGoPaths(fs, dir, time.Hour, nil))
+
+	for path, kept := range map[string]bool{stale: false, fresh: true, busy: true, lister: true, other: true} {
+		exists, err := afero.DirExists(fs, path)
+		r.NoError(err)
+		r.Equal(kept, exists, path)
+	}
+}
+
+// unreadableFs fails to open anything under dir, like the
+// GOPATHs another user's process left in a shared directory.
+type unreadableFs struct {
+	afero.Fs
+	dir string
+}
+
+func (fs unreadableFs) Open(name string) (afero.File, error) {
+	if strings.HasPrefix(name, fs.dir) {
+		return nil, os.ErrPermission
+	}
+	return fs.Fs.Open(name)
+}
+
+func (m *ModuleSuite) TestClearStaleGoPathsBestEffort() {
+	r := m.Require()
+	const dir = "/goget"
+	memFs := afero.NewMemMapFs()
+	old := time.Now().Add(-2 * time.Hour)
+	foreign := filepath.Join(dir, "athens-goget123")
+	stale := filepath.Join(dir, "athens-goget456")
+	for _, p := range []string{foreign, stale} {
+		r.NoError(createAndWriteFile(memFs, filepath.Join(p, "go.mod"), ""))
+		r.NoError(memFs.Chtimes(filepath.Join(p, "go.mod"), old, old))
+		r.NoError(memFs.Chtimes(p, old, old))
+	}
+	fs := unreadableFs{Fs: memFs, dir: foreign}
+
+	lggr := log.New("none", logrus.DebugLevel)
+	var buf bytes.Buffer
+	lggr.Out = &buf
+	r.NoError(clearStaleGoPaths(fs, dir, time.Hour, lggr.WithFields(nil)))
+
+	exists, err := afero.DirExists(memFs, foreign)
+	r.NoError(err)
+	r.True(exists, "expected the unreadable GOPATH to be skipped")
+	exists, err = afero.DirExists(memFs, stale)
+	r.NoError(err)
+	r.False(exists, "expected the other stale GOPATH to be removed")
+	r.Contains(buf.String(), "could not clear stale GOPATH "+foreign)
+}
+
+func (m *ModuleSuite) TestZipReadCloserKeepsGoPathFresh() {
+	r := m.Require()
+	fs := afero.NewMemMapFs()
+	gopath := "/goget/athens-goget123"
+	r.NoError(createAndWriteFile(fs, filepath.Join(gopath, "mod.zip"), "zip"))
+	old := time.Now().Add(-2 * time.Hour)
+	r.NoError(fs.Chtimes(filepath.Join(gopath, "mod.zip"), old, old))
+	r.NoError(fs.Chtimes(gopath, old, old))
+
+	zip, err := fs.Open(filepath.Join(gopath, "mod.zip"))
+	r.NoError(err)
+	rc := &zipReadCloser{zip: zip, fs: fs, goPath: gopath}
+	defer rc.Close()
+	_, err = rc.Read(make([]byte, 1))
+	r.NoError(err)
+
+	r.NoError(clearStaleGoPaths(fs, "/goget", time.Hour, nil))
+	exists, err := afero.DirExists(fs, gopath)
+	r.NoError(err)
+	r.True(exists, "expected the GOPATH of a zip being read to be kept")
+}
+
 // creates filename with fs, writes data to the file, and closes the file,
 //
 // returns a non-nil error if anything went wrong. the file will be closed
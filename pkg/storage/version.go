@@ -8,4 +8,13 @@ type Version struct {
 	Zip    io.ReadCloser
 	Info   []byte
 	Semver string
+	// Sum and GoModSum are the go.sum checksums of the zip and the
+	// .mod file, when known by whoever produced the Version.
+	Sum      string
+	GoModSum string
+	// TreeHash is the dirhash of the module's extracted source tree, as
+	// downloaded and before any rewriting, when recorded by the fetcher.
+	TreeHash string
+	// Origin is the VCS source the version was fetched from, when known.
+	Origin *Origin
 }